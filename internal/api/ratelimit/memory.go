@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// staleBucketTTL is how long a bucket can sit untouched before the sweep
+// evicts it. It only needs to exceed the longest time any Config actually
+// in use takes to refill an empty bucket (Burst/Rate × Per, which is just
+// Per when Burst defaults to Rate): a bucket this idle has long since
+// refilled to full, so dropping it loses no rate-limit state.
+const staleBucketTTL = time.Hour
+
+// sweepInterval is how often MemoryStore scans for and evicts stale
+// buckets. Without this, a credential-stuffing attack rotating through
+// many distinct IPs would grow buckets forever, turning the rate limiter
+// itself into a memory-exhaustion vector.
+const sweepInterval = 10 * time.Minute
+
+// MemoryStore is an in-process token-bucket Store. It's the default: fine
+// for a single API instance, but each instance enforces its own limit
+// independently, so a deployment with multiple instances behind a load
+// balancer should use RedisStore instead.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+// NewMemoryStore builds a MemoryStore and starts its background sweep; the
+// store is meant to live for the process's lifetime, so the sweep never
+// stops on its own.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		buckets: make(map[string]*bucket),
+	}
+
+	go s.sweepStaleBuckets()
+
+	return s
+}
+
+func (s *MemoryStore) sweepStaleBuckets() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-staleBucketTTL)
+
+		s.mu.Lock()
+		for key, b := range s.buckets {
+			if b.lastRefill.Before(cutoff) {
+				delete(s.buckets, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *MemoryStore) Allow(ctx context.Context, key string, cfg Config) (bool, time.Duration, error) {
+	if err := cfg.validate(); err != nil {
+		return false, 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	burst := float64(cfg.burst())
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, lastRefill: now}
+		s.buckets[key] = b
+	}
+
+	refillRate := float64(cfg.Rate) / cfg.Per.Seconds()
+	elapsed := now.Sub(b.lastRefill).Seconds()
+
+	b.tokens += elapsed * refillRate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.lastRefill = now
+
+	if b.tokens < 1 {
+		retryAfter := time.Duration((1 - b.tokens) / refillRate * float64(time.Second))
+		return false, retryAfter, nil
+	}
+
+	b.tokens--
+
+	return true, 0, nil
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// refillScript atomically refills and consumes a token from the bucket
+// stored at KEYS[1], so concurrent requests across API instances can't
+// race each other into over-consuming it. It stores tokens and the last
+// refill time as a hash and lets the key expire once the bucket would be
+// full again, so idle keys don't accumulate in Redis forever.
+var refillScript = redis.NewScript(`
+local key = KEYS[1]
+local rate = tonumber(ARGV[1])
+local per_seconds = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+
+local data = redis.call("HMGET", key, "tokens", "refilled_at")
+local tokens = tonumber(data[1])
+local refilled_at = tonumber(data[2])
+
+if tokens == nil then
+	tokens = burst
+	refilled_at = now
+end
+
+local elapsed = math.max(0, now - refilled_at)
+tokens = math.min(burst, tokens + elapsed * (rate / per_seconds))
+
+local allowed = 0
+if tokens >= 1 then
+	allowed = 1
+	tokens = tokens - 1
+end
+
+redis.call("HSET", key, "tokens", tostring(tokens), "refilled_at", tostring(now))
+redis.call("EXPIRE", key, math.ceil(per_seconds * burst / rate))
+
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore is a Store backed by Redis, so a rate limit is shared across
+// every API instance behind a load balancer rather than tracked per
+// instance like MemoryStore.
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Allow(ctx context.Context, key string, cfg Config) (bool, time.Duration, error) {
+	if err := cfg.validate(); err != nil {
+		return false, 0, err
+	}
+
+	now := time.Now()
+
+	result, err := refillScript.Run(
+		ctx, s.client,
+		[]string{key},
+		cfg.Rate, cfg.Per.Seconds(), cfg.burst(), now.Unix(),
+	).Result()
+	if err != nil {
+		return false, 0, fmt.Errorf("ratelimit: redis allow: %w", err)
+	}
+
+	values, ok := result.([]any)
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("ratelimit: unexpected redis script result %v", result)
+	}
+
+	allowed := values[0].(int64) == 1
+	if allowed {
+		return true, 0, nil
+	}
+
+	refillRate := float64(cfg.Rate) / cfg.Per.Seconds()
+	retryAfter := time.Duration(1 / refillRate * float64(time.Second))
+
+	return false, retryAfter, nil
+}
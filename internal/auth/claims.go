@@ -0,0 +1,53 @@
+// Package auth issues and validates the JWT access/refresh tokens used to
+// authenticate requests, replacing the opaque session tokens that used to
+// live entirely in internal/user.
+package auth
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Role mirrors the role values stored on a user's account. It is duplicated
+// here (rather than imported from internal/user) so this package has no
+// dependency on any particular resource package.
+type Role string
+
+const (
+	RoleCitizen   Role = "citizen"
+	RoleResponder Role = "responder"
+	RoleAdmin     Role = "admin"
+)
+
+// tokenType discriminates access tokens from refresh tokens in the `typ`
+// claim. Both are signed with the same HS256 secret and share most of
+// their fields, so without this a stolen refresh token could otherwise be
+// presented straight to AuthMiddleware (or an access token to
+// POST /auth/refresh) and would parse and verify just fine.
+type tokenType string
+
+const (
+	tokenTypeAccess  tokenType = "access"
+	tokenTypeRefresh tokenType = "refresh"
+)
+
+// Claims is the payload embedded in access tokens. TokenVersion is compared
+// against the user's current `token_version` column so that bumping it
+// (on password change or sign-out) invalidates every access token already
+// issued for that user without needing a per-token revocation record.
+type Claims struct {
+	jwt.RegisteredClaims
+
+	Type         tokenType `json:"typ"`
+	Role         Role      `json:"role"`
+	TokenVersion int       `json:"tokenVersion"`
+}
+
+// RefreshClaims is the payload embedded in refresh tokens. It carries no
+// role so that a stolen refresh token can't be used directly against
+// role-gated endpoints; it must first be exchanged for an access token.
+type RefreshClaims struct {
+	jwt.RegisteredClaims
+
+	Type         tokenType `json:"typ"`
+	TokenVersion int       `json:"tokenVersion"`
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidToken   = errors.New("auth: invalid token")
+	ErrExpiredToken   = errors.New("auth: expired token")
+	ErrRevokedToken   = errors.New("auth: revoked token")
+	ErrWrongTokenType = errors.New("auth: wrong token type")
+)
+
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// Issuer signs and parses access/refresh tokens. The zero value is not
+// usable; construct one with NewIssuer.
+type Issuer struct {
+	secret          []byte
+	issuer          string
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+}
+
+func NewIssuer(secret []byte, issuer string) *Issuer {
+	return &Issuer{
+		secret:          secret,
+		issuer:          issuer,
+		accessTokenTTL:  DefaultAccessTokenTTL,
+		refreshTokenTTL: DefaultRefreshTokenTTL,
+	}
+}
+
+// NewAccessToken issues a short-lived access token carrying the user's role
+// and current token version.
+func (i *Issuer) NewAccessToken(userID string, role Role, tokenVersion int) (string, error) {
+	now := time.Now()
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			Issuer:    i.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(i.accessTokenTTL)),
+			ID:        uuid.NewString(),
+		},
+		Type:         tokenTypeAccess,
+		Role:         role,
+		TokenVersion: tokenVersion,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	signed, err := token.SignedString(i.secret)
+	if err != nil {
+		return "", fmt.Errorf("new access token: %w", err)
+	}
+
+	return signed, nil
+}
+
+// NewRefreshToken issues a long-lived refresh token. It returns both the
+// signed token and its `jti` so the caller can persist it for rotation
+// bookkeeping if needed.
+func (i *Issuer) NewRefreshToken(userID string, tokenVersion int) (signed string, jti string, err error) {
+	now := time.Now()
+	jti = uuid.NewString()
+
+	claims := RefreshClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			Issuer:    i.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(i.refreshTokenTTL)),
+			ID:        jti,
+		},
+		Type:         tokenTypeRefresh,
+		TokenVersion: tokenVersion,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	signed, err = token.SignedString(i.secret)
+	if err != nil {
+		return "", "", fmt.Errorf("new refresh token: %w", err)
+	}
+
+	return signed, jti, nil
+}
+
+func (i *Issuer) ParseAccessToken(raw string) (*Claims, error) {
+	claims := &Claims{}
+
+	_, err := jwt.ParseWithClaims(raw, claims, i.keyFunc)
+	if err != nil {
+		return nil, mapParseError(err)
+	}
+
+	if claims.Type != tokenTypeAccess {
+		return nil, ErrWrongTokenType
+	}
+
+	return claims, nil
+}
+
+func (i *Issuer) ParseRefreshToken(raw string) (*RefreshClaims, error) {
+	claims := &RefreshClaims{}
+
+	_, err := jwt.ParseWithClaims(raw, claims, i.keyFunc)
+	if err != nil {
+		return nil, mapParseError(err)
+	}
+
+	if claims.Type != tokenTypeRefresh {
+		return nil, ErrWrongTokenType
+	}
+
+	return claims, nil
+}
+
+func (i *Issuer) keyFunc(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return i.secret, nil
+}
+
+func mapParseError(err error) error {
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return ErrExpiredToken
+	}
+
+	return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DefaultLockoutThreshold is how many consecutive failed sign-in
+	// attempts for an email trigger a lockout.
+	DefaultLockoutThreshold = 5
+
+	// DefaultLockoutWindow is how long a run of failures must stay within
+	// to count toward DefaultLockoutThreshold; an older failure doesn't
+	// contribute once this much time has passed since it happened.
+	DefaultLockoutWindow = 15 * time.Minute
+
+	// DefaultLockoutDuration is how long an email is rejected for once
+	// locked out.
+	DefaultLockoutDuration = 15 * time.Minute
+)
+
+// LockoutStore tracks consecutive failed sign-in attempts per email,
+// independent of the per-IP rate limiting in internal/api/ratelimit: an
+// attacker rotating IPs against one victim email is still caught here.
+type LockoutStore interface {
+	// RecordFailure registers a failed sign-in attempt for email. locked
+	// reports whether the failure count has now reached the threshold;
+	// retryAfter is how long further attempts should be rejected for.
+	RecordFailure(ctx context.Context, email string) (locked bool, retryAfter time.Duration, err error)
+
+	// Locked reports whether email is currently locked out, and if so for
+	// how much longer.
+	Locked(ctx context.Context, email string) (locked bool, retryAfter time.Duration, err error)
+
+	// Reset clears email's failure count, called after a successful sign-in.
+	Reset(ctx context.Context, email string) error
+}
+
+// PostgresLockoutStore persists lockout state in a `login_lockouts` table:
+// (email text primary key, failure_count int not null, first_failed_at
+// timestamptz not null, locked_until timestamptz).
+type PostgresLockoutStore struct {
+	pool      *pgxpool.Pool
+	threshold int
+	window    time.Duration
+	duration  time.Duration
+}
+
+// NewPostgresLockoutStore builds a store using the default threshold,
+// window, and lockout duration.
+func NewPostgresLockoutStore(pool *pgxpool.Pool) *PostgresLockoutStore {
+	return &PostgresLockoutStore{
+		pool:      pool,
+		threshold: DefaultLockoutThreshold,
+		window:    DefaultLockoutWindow,
+		duration:  DefaultLockoutDuration,
+	}
+}
+
+func (s *PostgresLockoutStore) Locked(ctx context.Context, email string) (bool, time.Duration, error) {
+	var lockedUntil *time.Time
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT locked_until FROM login_lockouts WHERE email = $1`,
+		email,
+	).Scan(&lockedUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, 0, nil
+		}
+
+		return false, 0, fmt.Errorf("check lockout: %w", err)
+	}
+
+	if lockedUntil == nil || !lockedUntil.After(time.Now()) {
+		return false, 0, nil
+	}
+
+	return true, time.Until(*lockedUntil), nil
+}
+
+// RecordFailure upserts the email's failure row: a failure inside the
+// current window increments the count, one outside it starts a fresh
+// window at count 1. Crossing the threshold sets locked_until. The
+// read-modify-write happens inside one transaction so concurrent failed
+// attempts for the same email can't race each other into undercounting.
+func (s *PostgresLockoutStore) RecordFailure(ctx context.Context, email string) (bool, time.Duration, error) {
+	now := time.Now()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, 0, fmt.Errorf("record login failure: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var failureCount int
+	var firstFailedAt time.Time
+
+	err = tx.QueryRow(
+		ctx,
+		`SELECT failure_count, first_failed_at FROM login_lockouts WHERE email = $1 FOR UPDATE`,
+		email,
+	).Scan(&failureCount, &firstFailedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, 0, fmt.Errorf("record login failure: %w", err)
+	}
+
+	if err != nil || now.Sub(firstFailedAt) > s.window {
+		failureCount = 1
+		firstFailedAt = now
+	} else {
+		failureCount++
+	}
+
+	var lockedUntil *time.Time
+	if failureCount >= s.threshold {
+		until := now.Add(s.duration)
+		lockedUntil = &until
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO login_lockouts (email, failure_count, first_failed_at, locked_until)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (email) DO UPDATE SET
+		   failure_count = $2, first_failed_at = $3, locked_until = $4`,
+		email, failureCount, firstFailedAt, lockedUntil,
+	)
+	if err != nil {
+		return false, 0, fmt.Errorf("record login failure: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, 0, fmt.Errorf("record login failure: %w", err)
+	}
+
+	if lockedUntil == nil {
+		return false, 0, nil
+	}
+
+	return true, time.Until(*lockedUntil), nil
+}
+
+func (s *PostgresLockoutStore) Reset(ctx context.Context, email string) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM login_lockouts WHERE email = $1`, email)
+	if err != nil {
+		return fmt.Errorf("reset lockout: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/InternalPointerVariable/ResQLink-Backend/internal/api/ratelimit"
+)
+
+// Suggested per-route ratelimit.Config values for the /auth/* endpoints,
+// tuned to stop credential-stuffing and sign-up spam without getting in a
+// legitimate user's way. The composition root wires these into a
+// ratelimit.Limiter alongside CSRFMiddleware and AuthMiddleware.
+var (
+	// SignInRateLimit allows 5 sign-in attempts per IP per minute.
+	SignInRateLimit = ratelimit.Config{Rate: 5, Per: time.Minute}
+
+	// SignUpRateLimit allows 3 account creations per IP per hour.
+	SignUpRateLimit = ratelimit.Config{Rate: 3, Per: time.Hour}
+
+	// SignInAnonymousRateLimit matches SignInRateLimit: anonymous sign-in
+	// still mints real tokens and shouldn't be cheaper to abuse.
+	SignInAnonymousRateLimit = ratelimit.Config{Rate: 5, Per: time.Minute}
+)
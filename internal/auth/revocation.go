@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RevocationStore tracks access-token `jti`s that have been explicitly
+// revoked (e.g. via POST /auth/revoke) before their natural expiry. Most
+// invalidation instead goes through the `token_version` claim, which
+// requires no per-token writes; this store only needs to hold entries until
+// the token's own exp would have retired it anyway.
+type RevocationStore interface {
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// PostgresRevocationStore persists revoked `jti`s in a `revoked_tokens`
+// table: (jti text primary key, expires_at timestamptz not null).
+type PostgresRevocationStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRevocationStore(pool *pgxpool.Pool) *PostgresRevocationStore {
+	return &PostgresRevocationStore{pool: pool}
+}
+
+func (s *PostgresRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := s.pool.Exec(
+		ctx,
+		`INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2)
+		 ON CONFLICT (jti) DO NOTHING`,
+		jti,
+		expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var exists bool
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1 AND expires_at > now())`,
+		jti,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check revoked token: %w", err)
+	}
+
+	return exists, nil
+}
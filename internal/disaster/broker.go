@@ -0,0 +1,128 @@
+package disaster
+
+import (
+	"context"
+	"sync"
+)
+
+// EventType identifies what changed about a disaster report for the
+// purposes of the live SSE feed.
+type EventType string
+
+const (
+	EventCreated       EventType = "created"
+	EventAssigned      EventType = "assigned"
+	EventStatusChanged EventType = "status_changed"
+)
+
+// subscriberBufferSize bounds how many unconsumed events a single SSE
+// subscriber can queue before Broker drops it rather than letting a slow
+// responder app block publication for everyone else.
+const subscriberBufferSize = 32
+
+// eventHistoryLimit bounds how many recent events Broker retains for
+// replay. A client that reconnects after missing more than this many
+// events has fallen too far behind for Last-Event-ID resume to recover
+// and should fall back to refetching the current state instead.
+const eventHistoryLimit = 256
+
+// Event is a single report-state change, stamped with an incrementing ID
+// so subscribers can resume via the SSE Last-Event-ID header.
+type Event struct {
+	ID   uint64
+	Type EventType
+	Data any
+}
+
+// Broker is an in-process pub/sub for disaster-report changes. It retains
+// only the last eventHistoryLimit events for replay, so it can resume a
+// client that reconnects after a short gap but, like any non-durable
+// broker, can't help one that's been gone longer than that.
+type Broker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	subscribers map[chan Event]struct{}
+	history     []Event
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber and returns a channel of events.
+// The channel is closed once ctx is done or the subscriber is dropped for
+// being too slow.
+func (b *Broker) Subscribe(ctx context.Context) <-chan Event {
+	ch, _ := b.SubscribeFrom(ctx, 0)
+	return ch
+}
+
+// SubscribeFrom registers a new subscriber like Subscribe, but also returns
+// any retained events published after lastEventID, so a client resuming via
+// the SSE Last-Event-ID header doesn't silently lose events published
+// during its disconnection gap. lastEventID of 0 means no replay, which is
+// what a client's first connection should pass.
+func (b *Broker) SubscribeFrom(ctx context.Context, lastEventID uint64) (<-chan Event, []Event) {
+	ch := make(chan Event, subscriberBufferSize)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+
+	var replay []Event
+	if lastEventID > 0 {
+		for _, event := range b.history {
+			if event.ID > lastEventID {
+				replay = append(replay, event)
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.unsubscribe(ch)
+	}()
+
+	return ch, replay
+}
+
+func (b *Broker) unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Publish fans data out to every current subscriber under eventType,
+// stamping it with the next monotonic event ID. A subscriber whose buffer
+// is already full is dropped instead of blocking the publish; its
+// responder app will reconnect and pick up from the last ID it saw.
+func (b *Broker) Publish(eventType EventType, data any) {
+	b.mu.Lock()
+	b.lastID++
+	event := Event{ID: b.lastID, Type: eventType, Data: data}
+
+	b.history = append(b.history, event)
+	if len(b.history) > eventHistoryLimit {
+		b.history = b.history[len(b.history)-eventHistoryLimit:]
+	}
+
+	subscribers := make([]chan Event, 0, len(b.subscribers))
+	for ch := range b.subscribers {
+		subscribers = append(subscribers, ch)
+	}
+	b.mu.Unlock()
+
+	for _, ch := range subscribers {
+		select {
+		case ch <- event:
+		default:
+			b.unsubscribe(ch)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package disaster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/rwcarlsen/goexif/exif"
+
+	"github.com/InternalPointerVariable/ResQLink-Backend/internal/storage"
+)
+
+// thumbnailWidth is the target width, in pixels, of generated thumbnails;
+// height scales to preserve aspect ratio.
+const thumbnailWidth = 512
+
+// reportPhoto is what a single uploaded photo resolves to once it's been
+// through the EXIF/thumbnail pipeline: the stored (EXIF-stripped) image key,
+// its thumbnail key, and whatever geotag/timestamp could be recovered.
+type reportPhoto struct {
+	Key          string     `json:"key"`
+	ThumbnailKey string     `json:"thumbnailKey"`
+	Latitude     *float64   `json:"latitude,omitempty"`
+	Longitude    *float64   `json:"longitude,omitempty"`
+	TakenAt      *time.Time `json:"takenAt,omitempty"`
+}
+
+// processPhoto runs an uploaded image through the full pipeline: it rejects
+// decoded dimensions above maxDimension (defense against decompression-bomb
+// PNGs), extracts EXIF geotag/timestamp, re-encodes the image without EXIF
+// to protect reporter privacy, generates a thumbnail, and uploads both
+// under key and "thumb/"+key. key's extension is adjusted to match
+// whatever format encodeImage actually produced, since that can differ
+// from the upload's original format (e.g. WebP is decode-only and always
+// re-encodes to JPEG).
+func processPhoto(
+	ctx context.Context,
+	store storage.Storage,
+	key string,
+	data []byte,
+	maxDimension int,
+) (reportPhoto, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return reportPhoto{}, fmt.Errorf("process photo: decode config: %w", err)
+	}
+
+	if cfg.Width > maxDimension || cfg.Height > maxDimension {
+		return reportPhoto{}, fmt.Errorf(
+			"process photo: dimensions %dx%d exceed max %dpx",
+			cfg.Width, cfg.Height, maxDimension,
+		)
+	}
+
+	photo := reportPhoto{}
+
+	if lat, long, takenAt, ok := extractEXIF(data); ok {
+		photo.Latitude = lat
+		photo.Longitude = long
+		photo.TakenAt = takenAt
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return reportPhoto{}, fmt.Errorf("process photo: decode image: %w", err)
+	}
+
+	stripped, contentType, err := encodeImage(img, format)
+	if err != nil {
+		return reportPhoto{}, fmt.Errorf("process photo: re-encode stripped image: %w", err)
+	}
+	key = withExtension(key, contentType)
+
+	if _, err := store.Put(ctx, key, contentType, bytes.NewReader(stripped)); err != nil {
+		return reportPhoto{}, fmt.Errorf("process photo: upload image: %w", err)
+	}
+	photo.Key = key
+
+	thumbnail := imaging.Resize(img, thumbnailWidth, 0, imaging.Lanczos)
+
+	encodedThumbnail, _, err := encodeImage(thumbnail, format)
+	if err != nil {
+		return reportPhoto{}, fmt.Errorf("process photo: encode thumbnail: %w", err)
+	}
+
+	thumbnailKey := "thumb/" + key
+	if _, err := store.Put(ctx, thumbnailKey, contentType, bytes.NewReader(encodedThumbnail)); err != nil {
+		return reportPhoto{}, fmt.Errorf("process photo: upload thumbnail: %w", err)
+	}
+	photo.ThumbnailKey = thumbnailKey
+
+	return photo, nil
+}
+
+// extractEXIF pulls GPS coordinates and the original capture time out of an
+// image's EXIF data. ok is false when the image carries no usable EXIF
+// (common for re-encoded/screenshotted photos), in which case the caller
+// should fall back to the reporter's shared location.
+func extractEXIF(data []byte) (lat, long *float64, takenAt *time.Time, ok bool) {
+	x, err := exif.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, nil, false
+	}
+
+	found := false
+
+	if latVal, longVal, err := x.LatLong(); err == nil {
+		lat, long = &latVal, &longVal
+		found = true
+	}
+
+	if dt, err := x.DateTime(); err == nil {
+		takenAt = &dt
+		found = true
+	}
+
+	return lat, long, takenAt, found
+}
+
+// encodeImage re-encodes img using a plain encoder (no EXIF/metadata
+// segments are carried over), matching the original format where the
+// standard library supports it and falling back to JPEG otherwise (the
+// standard library and golang.org/x/image can decode several formats, such
+// as WebP, that they have no encoder for). It returns the content type the
+// bytes were actually encoded as, which the caller must use in place of
+// the upload's original content type.
+func encodeImage(img image.Image, format string) (data []byte, contentType string, err error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case "png":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		contentType = "image/png"
+	default:
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, "", err
+		}
+		contentType = "image/jpeg"
+	}
+
+	return buf.Bytes(), contentType, nil
+}
+
+// extensionsByContentType maps a re-encoded image's content type to the file
+// extension its storage key should carry.
+var extensionsByContentType = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+}
+
+// withExtension replaces key's file extension with the one matching
+// contentType, so a photo whose format changed during re-encoding (e.g.
+// WebP re-encoded to JPEG) isn't stored under a key whose extension no
+// longer matches its actual bytes.
+func withExtension(key, contentType string) string {
+	return strings.TrimSuffix(key, filepath.Ext(key)) + extensionsByContentType[contentType]
+}
@@ -3,20 +3,65 @@ package disaster
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/InternalPointerVariable/ResQLink-Backend/internal/api"
+	"github.com/InternalPointerVariable/ResQLink-Backend/internal/auth"
+	"github.com/InternalPointerVariable/ResQLink-Backend/internal/storage"
+	"github.com/google/uuid"
+
+	// Registers the "webp" format with image.Decode/image.DecodeConfig, so
+	// processPhoto's decoder can actually read what allowedPhotoTypes
+	// advertises accepting.
+	_ "golang.org/x/image/webp"
+)
+
+// allowedPhotoTypes is the MIME whitelist for uploaded report photos,
+// checked against the multipart part's declared Content-Type. Limited to
+// formats processPhoto can actually decode; HEIC has no practical pure-Go
+// decoder, so it's rejected here rather than passing this check and then
+// failing every upload in processPhoto.
+var allowedPhotoTypes = map[string]struct{}{
+	"image/jpeg": {},
+	"image/png":  {},
+	"image/webp": {},
+}
+
+const (
+	maxBodySize       = 10 << 20
+	maxPhotoSize      = 8 << 20
+	maxTotalPhotoSize = 32 << 20
+
+	photoSignedURLTTL = 15 * time.Minute
+
+	// defaultMaxImageDimension bounds decoded image width/height as a
+	// defense against decompression-bomb PNGs (a small file that decodes
+	// to a huge pixel buffer).
+	defaultMaxImageDimension = 8192
+
+	// sseHeartbeatInterval is how often StreamDisasterReports sends a
+	// keep-alive comment, so proxies and load balancers don't time out an
+	// otherwise-idle connection.
+	sseHeartbeatInterval = 15 * time.Second
 )
 
 type Server struct {
-	repository Repository
-	baseURL    string
+	repository        Repository
+	storage           storage.Storage
+	broker            *Broker
+	maxImageDimension int
 }
 
-func NewServer(repository Repository, baseURL string) *Server {
+func NewServer(repository Repository, storage storage.Storage) *Server {
 	return &Server{
-		repository: repository,
-		baseURL:    baseURL,
+		repository:        repository,
+		storage:           storage,
+		broker:            NewBroker(),
+		maxImageDimension: defaultMaxImageDimension,
 	}
 }
 
@@ -50,6 +95,12 @@ type createReportRequest struct {
 	Status       citizenStatus `json:"status"`
 	RawSituation string        `json:"rawSituation"`
 	PhotoURLs    []string      `json:"photoUrls"`
+
+	// Photos holds the per-photo results of CreateDisasterReport's upload
+	// pipeline (storage key, thumbnail key, EXIF geotag/timestamp). It's
+	// stored instead of PhotoURLs so GET URLs can be re-signed later
+	// rather than going stale with an S3 backend.
+	Photos []reportPhoto `json:"-"`
 }
 
 // NOTE: This is a version of `CreateDisasterReport` that uses `application/json`
@@ -76,19 +127,25 @@ func (s *Server) CreateDisasterReportJson(w http.ResponseWriter, r *http.Request
 		}
 	}
 
+	s.broker.Publish(EventCreated, data)
+
 	return api.Response{
 		Code:    http.StatusCreated,
 		Message: "Successfully created disaster report.",
 	}
 }
 
+// CreateDisasterReport streams each multipart form part rather than
+// buffering the whole upload to disk first; non-file fields are read
+// directly, and each photo is read fully into memory (bounded by
+// maxPhotoSize) only for as long as its EXIF/thumbnail pipeline needs it.
 func (s *Server) CreateDisasterReport(w http.ResponseWriter, r *http.Request) api.Response {
 	ctx := r.Context()
 
-	const maxBodySize = 10 << 20
 	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
 
-	if err := r.ParseMultipartForm(maxBodySize); err != nil {
+	reader, err := r.MultipartReader()
+	if err != nil {
 		return api.Response{
 			Error:   fmt.Errorf("create disaster report: %w", err),
 			Code:    http.StatusBadRequest,
@@ -96,36 +153,147 @@ func (s *Server) CreateDisasterReport(w http.ResponseWriter, r *http.Request) ap
 		}
 	}
 
-	var userID *string
-	userIDstr := r.FormValue("userId")
-	if userIDstr != "" {
-		userID = &userIDstr
+	disasterReport := createReportRequest{
+		PhotoURLs: []string{},
+		Photos:    []reportPhoto{},
 	}
 
-	disasterReport := createReportRequest{
-		UserID:       userID,
-		Name:         r.FormValue("name"),
-		Status:       citizenStatus(r.FormValue("status")),
-		RawSituation: r.FormValue("rawSituation"),
-		PhotoURLs:    []string{},
-	}
-
-	if r.MultipartForm != nil && r.MultipartForm.File != nil {
-		photos := r.MultipartForm.File["photos"]
-
-		if len(photos) > 0 {
-			for _, fileHeader := range photos {
-				fileURL, err := uploadPhoto(fileHeader, s.baseURL)
-				if err != nil {
-					return api.Response{
-						Error:   fmt.Errorf("create disaster report: %w", err),
-						Code:    http.StatusInternalServerError,
-						Message: "Failed to upload photo.",
-					}
+	var totalPhotoBytes int64
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return api.Response{
+				Error:   fmt.Errorf("create disaster report: %w", err),
+				Code:    http.StatusBadRequest,
+				Message: "Failed to parse disaster report form data.",
+			}
+		}
+
+		switch part.FormName() {
+		case "userId":
+			value, err := readPartString(part)
+			if err != nil {
+				return api.Response{
+					Error:   fmt.Errorf("create disaster report: %w", err),
+					Code:    http.StatusBadRequest,
+					Message: "Failed to parse disaster report form data.",
+				}
+			}
+			if value != "" {
+				disasterReport.UserID = &value
+			}
+		case "name":
+			value, err := readPartString(part)
+			if err != nil {
+				return api.Response{
+					Error:   fmt.Errorf("create disaster report: %w", err),
+					Code:    http.StatusBadRequest,
+					Message: "Failed to parse disaster report form data.",
+				}
+			}
+			disasterReport.Name = value
+		case "status":
+			value, err := readPartString(part)
+			if err != nil {
+				return api.Response{
+					Error:   fmt.Errorf("create disaster report: %w", err),
+					Code:    http.StatusBadRequest,
+					Message: "Failed to parse disaster report form data.",
+				}
+			}
+			disasterReport.Status = citizenStatus(value)
+		case "rawSituation":
+			value, err := readPartString(part)
+			if err != nil {
+				return api.Response{
+					Error:   fmt.Errorf("create disaster report: %w", err),
+					Code:    http.StatusBadRequest,
+					Message: "Failed to parse disaster report form data.",
+				}
+			}
+			disasterReport.RawSituation = value
+		case "photos":
+			contentType := part.Header.Get("Content-Type")
+			if _, ok := allowedPhotoTypes[contentType]; !ok {
+				return api.Response{
+					Error:   fmt.Errorf("create disaster report: unsupported photo content type %q", contentType),
+					Code:    http.StatusUnsupportedMediaType,
+					Message: "Photos must be JPEG, PNG, or WebP.",
 				}
+			}
 
-				disasterReport.PhotoURLs = append(disasterReport.PhotoURLs, fileURL)
+			if totalPhotoBytes >= maxTotalPhotoSize {
+				return api.Response{
+					Error:   fmt.Errorf("create disaster report: aggregate photo size exceeds %d bytes", maxTotalPhotoSize),
+					Code:    http.StatusRequestEntityTooLarge,
+					Message: "Photos are too large.",
+				}
 			}
+
+			// The EXIF/thumbnail pipeline needs the whole image decoded
+			// in memory, so unlike the other form fields this can't
+			// stream straight to storage; it's still bounded by
+			// maxPhotoSize.
+			data, err := io.ReadAll(io.LimitReader(part, maxPhotoSize+1))
+			if err != nil {
+				return api.Response{
+					Error:   fmt.Errorf("create disaster report: %w", err),
+					Code:    http.StatusBadRequest,
+					Message: "Failed to read photo.",
+				}
+			}
+			if int64(len(data)) > maxPhotoSize {
+				return api.Response{
+					Error:   fmt.Errorf("create disaster report: photo exceeds %d bytes", maxPhotoSize),
+					Code:    http.StatusRequestEntityTooLarge,
+					Message: "Photo is too large.",
+				}
+			}
+
+			filename := sanitizePhotoFilename(part.FileName())
+			key := fmt.Sprintf("reports/%s/%s", uuid.NewString(), filename)
+
+			photo, err := processPhoto(ctx, s.storage, key, data, s.maxImageDimension)
+			if err != nil {
+				return api.Response{
+					Error:   fmt.Errorf("create disaster report: %w", err),
+					Code:    http.StatusUnprocessableEntity,
+					Message: "Failed to process photo.",
+				}
+			}
+
+			totalPhotoBytes += int64(len(data))
+			disasterReport.Photos = append(disasterReport.Photos, photo)
+		}
+	}
+
+	// Citizen reporters are identified from their session rather than the
+	// form body; anonymous/unauthenticated reports still fall back to the
+	// client-supplied userId so walk-in reports keep working.
+	if authenticatedID, ok := auth.UserID(ctx); ok {
+		disasterReport.UserID = &authenticatedID
+	}
+
+	// Fall back to the reporter's own shared location for any photo whose
+	// EXIF carried no GPS tag (common for re-encoded or screenshotted
+	// images), so responders can still pin the report on a map.
+	if disasterReport.UserID != nil {
+		for i := range disasterReport.Photos {
+			if disasterReport.Photos[i].Latitude != nil {
+				continue
+			}
+
+			lat, long, err := s.repository.ReporterLocation(ctx, *disasterReport.UserID)
+			if err != nil || lat == nil || long == nil {
+				continue
+			}
+
+			disasterReport.Photos[i].Latitude = lat
+			disasterReport.Photos[i].Longitude = long
 		}
 	}
 
@@ -137,12 +305,44 @@ func (s *Server) CreateDisasterReport(w http.ResponseWriter, r *http.Request) ap
 		}
 	}
 
+	s.broker.Publish(EventCreated, disasterReport)
+
 	return api.Response{
 		Code:    http.StatusCreated,
 		Message: "Successfully created disaster report.",
 	}
 }
 
+// readPartString reads a non-file multipart part as a UTF-8 string, up to
+// 4 KiB; anything past that is dropped.
+func readPartString(part io.Reader) (string, error) {
+	b, err := io.ReadAll(io.LimitReader(part, 4<<10))
+	if err != nil {
+		return "", fmt.Errorf("read form part: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// sanitizePhotoFilename strips any directory components from an uploaded
+// file's client-supplied name before it's used as part of a storage key:
+// part.FileName() is attacker-controlled, and passing something like
+// "../../../../tmp/evil.jpg" straight through would let a report submitter
+// write outside the storage backend's intended directory. Empty or
+// dot-only names (".", "..") fall back to a fixed name instead.
+func sanitizePhotoFilename(name string) string {
+	name = filepath.Base(filepath.FromSlash(name))
+
+	if name == "" || name == "." || name == ".." || name == string(filepath.Separator) {
+		return "photo"
+	}
+
+	return name
+}
+
+// ListDisasterReports fetches report rows and, for reports whose photos are
+// stored by key rather than a stable URL, re-signs each one against
+// s.storage so links handed to the client are always fresh.
 func (s *Server) ListDisasterReports(w http.ResponseWriter, r *http.Request) api.Response {
 	ctx := r.Context()
 
@@ -155,6 +355,28 @@ func (s *Server) ListDisasterReports(w http.ResponseWriter, r *http.Request) api
 		}
 	}
 
+	for i, report := range reports {
+		if len(report.Photos) == 0 {
+			continue
+		}
+
+		urls := make([]string, len(report.Photos))
+		for j, photo := range report.Photos {
+			url, err := s.storage.SignedURL(ctx, photo.Key, photoSignedURLTTL)
+			if err != nil {
+				return api.Response{
+					Error:   fmt.Errorf("get disaster reports: sign photo url: %w", err),
+					Code:    http.StatusInternalServerError,
+					Message: "Failed to get disaster reports.",
+				}
+			}
+
+			urls[j] = url
+		}
+
+		reports[i].PhotoURLs = urls
+	}
+
 	return api.Response{
 		Code:    http.StatusOK,
 		Message: "Successfully fetched disaster reports.",
@@ -185,6 +407,20 @@ func (s *Server) SetResponder(w http.ResponseWriter, r *http.Request) api.Respon
 		}
 	}
 
+	// The caller's own ID, not whatever the request body claims, is who
+	// gets recorded as the responder: RequireRole(responder) only proves
+	// the caller holds the responder role, not that they are the specific
+	// responder named in data.ResponderID.
+	responderID, ok := auth.UserID(ctx)
+	if !ok {
+		return api.Response{
+			Error:   fmt.Errorf("set responder: missing authenticated user"),
+			Code:    http.StatusUnauthorized,
+			Message: "Failed to set responder.",
+		}
+	}
+	data.ResponderID = responderID
+
 	resp, err := s.repository.SetResponder(ctx, data)
 	if err != nil {
 		return api.Response{
@@ -194,9 +430,90 @@ func (s *Server) SetResponder(w http.ResponseWriter, r *http.Request) api.Respon
 		}
 	}
 
+	s.broker.Publish(EventAssigned, resp)
+
 	return api.Response{
 		Code:    http.StatusOK,
 		Data:    resp,
 		Message: "Successfully set responder.",
 	}
 }
+
+// StreamDisasterReports serves GET /disasters/stream: an SSE connection,
+// gated by AuthMiddleware plus RequireRole(responder), that pushes Broker
+// events to the responder app as they happen. Unlike this package's other
+// handlers it writes directly to the ResponseWriter instead of returning
+// api.Response, since a long-lived stream doesn't fit a single JSON
+// envelope.
+//
+// A responder app that reconnects after a drop sends back the last event
+// ID it saw via the Last-Event-ID header (EventSource does this
+// automatically); s.broker replays whatever it still has buffered for that
+// ID before the handler starts pushing new events.
+func (s *Server) StreamDisasterReports(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported.", http.StatusInternalServerError)
+		return
+	}
+
+	ctx := r.Context()
+
+	var lastEventID uint64
+	if raw := r.Header.Get("Last-Event-ID"); raw != "" {
+		if id, err := strconv.ParseUint(raw, 10, 64); err == nil {
+			lastEventID = id
+		}
+	}
+
+	events, replay := s.broker.SubscribeFrom(ctx, lastEventID)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for _, event := range replay {
+		payload, err := json.Marshal(event.Data)
+		if err != nil {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, payload); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			payload, err := json.Marshal(event.Data)
+			if err != nil {
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, payload); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
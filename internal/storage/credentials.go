@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// staticCredentials wraps a fixed access key/secret pair (used for MinIO and
+// other self-hosted S3-compatible backends) as an aws.CredentialsProvider.
+// Leaving both fields empty returns nil, so the client is built with no
+// credentials provider; the SDK's default credential chain (env vars,
+// shared config, instance role) is not loaded.
+func staticCredentials(accessKeyID, secretKey string) aws.CredentialsProvider {
+	if accessKeyID == "" && secretKey == "" {
+		return nil
+	}
+
+	return aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		return aws.Credentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretKey,
+		}, nil
+	})
+}
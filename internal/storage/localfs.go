@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LocalFS stores objects on local disk under dir and serves them back at
+// baseURL+"/"+key. It's the default backend and preserves the behavior
+// disaster.uploadPhoto had before object storage was introduced.
+type LocalFS struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocalFS(dir, baseURL string) *LocalFS {
+	return &LocalFS{dir: dir, baseURL: baseURL}
+}
+
+func (l *LocalFS) Put(ctx context.Context, key string, contentType string, r io.Reader) (string, error) {
+	path := filepath.Join(l.dir, filepath.FromSlash(key))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("localfs put: %w", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("localfs put: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, r); err != nil {
+		return "", fmt.Errorf("localfs put: %w", err)
+	}
+
+	return l.baseURL + "/" + key, nil
+}
+
+func (l *LocalFS) Delete(ctx context.Context, key string) error {
+	path := filepath.Join(l.dir, filepath.FromSlash(key))
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("localfs delete: %w", err)
+	}
+
+	return nil
+}
+
+// SignedURL ignores ttl: local files are served at a stable, unsigned URL.
+func (l *LocalFS) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return l.baseURL + "/" + key, nil
+}
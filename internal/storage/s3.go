@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3 stores objects in an S3-compatible bucket. Pointing Endpoint at a
+// MinIO instance (and setting UsePathStyle) makes it work the same way
+// against a self-hosted bucket.
+type S3 struct {
+	client *s3.Client
+	bucket string
+}
+
+// S3Config configures the S3 backend. Endpoint is optional and, when set,
+// is used instead of AWS's regional endpoints (MinIO, other S3-compatible
+// providers).
+type S3Config struct {
+	Bucket        string
+	Region        string
+	Endpoint      string
+	UsePathStyle  bool
+	AccessKeyID   string
+	SecretKey     string
+}
+
+func NewS3(cfg S3Config) *S3 {
+	var endpoint *string
+	if cfg.Endpoint != "" {
+		endpoint = aws.String(cfg.Endpoint)
+	}
+
+	client := s3.New(s3.Options{
+		Region:       cfg.Region,
+		BaseEndpoint: endpoint,
+		UsePathStyle: cfg.UsePathStyle,
+		Credentials:  staticCredentials(cfg.AccessKeyID, cfg.SecretKey),
+	})
+
+	return &S3{client: client, bucket: cfg.Bucket}
+}
+
+func (s *S3) Put(ctx context.Context, key string, contentType string, r io.Reader) (string, error) {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        r,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("s3 put: %w", err)
+	}
+
+	return s.SignedURL(ctx, key, DefaultSignedURLTTL)
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 delete: %w", err)
+	}
+
+	return nil
+}
+
+// DefaultSignedURLTTL is used for the URL returned by Put immediately after
+// upload, before a caller has a chance to specify its own TTL.
+const DefaultSignedURLTTL = 15 * time.Minute
+
+func (s *S3) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	presigner := s3.NewPresignClient(s.client)
+
+	req, err := presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("s3 signed url: %w", err)
+	}
+
+	return req.URL, nil
+}
@@ -0,0 +1,28 @@
+// Package storage abstracts where disaster-report photos actually live, so
+// internal/disaster can stream uploads to local disk or an S3-compatible
+// bucket (MinIO included) through the same interface.
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Storage puts, deletes, and signs URLs for binary objects addressed by an
+// opaque key. Implementations persist nothing about the key's meaning;
+// callers (internal/disaster) decide the key layout, e.g. "reports/<id>/<photo>.jpg"
+// and "thumb/reports/<id>/<photo>.jpg".
+type Storage interface {
+	// Put streams r to the backend under key and returns a URL clients can
+	// use to fetch it. For backends where the returned URL isn't durable
+	// (e.g. a pre-signed S3 URL), callers should persist key rather than
+	// the URL and call SignedURL again on read.
+	Put(ctx context.Context, key string, contentType string, r io.Reader) (url string, err error)
+
+	Delete(ctx context.Context, key string) error
+
+	// SignedURL returns a URL valid for ttl that can be used to GET key.
+	// LocalFS implementations may ignore ttl and return a stable URL.
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
@@ -0,0 +1,154 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/InternalPointerVariable/ResQLink-Backend/internal/api"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// GetUsers lists every account, for admin onboarding/offboarding screens.
+func (s *Server) GetUsers(w http.ResponseWriter, r *http.Request) api.Response {
+	ctx := r.Context()
+
+	users, err := s.repository.ListUsers(ctx)
+	if err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("get users: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get users.",
+		}
+	}
+
+	return api.Response{
+		Code:    http.StatusOK,
+		Message: "Successfully fetched users.",
+		Data:    users,
+	}
+}
+
+type adminCreateUserRequest struct {
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Role      role   `json:"role"`
+}
+
+// AdminUsersCreate onboards a responder or admin account directly, bypassing
+// the public sign-up flow's citizen-only default role.
+func (s *Server) AdminUsersCreate(w http.ResponseWriter, r *http.Request) api.Response {
+	ctx := r.Context()
+
+	var data adminCreateUserRequest
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&data); err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("admin create user: %w", err),
+			Code:    http.StatusBadRequest,
+			Message: "Invalid create user request.",
+		}
+	}
+
+	if err := s.repository.AdminCreateUser(ctx, data); err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+			return api.Response{
+				Error:   fmt.Errorf("admin create user: %w", err),
+				Code:    http.StatusConflict,
+				Message: "User " + data.Email + " already exists.",
+			}
+		}
+
+		return api.Response{
+			Error:   fmt.Errorf("admin create user: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to create user.",
+		}
+	}
+
+	return api.Response{
+		Code:    http.StatusCreated,
+		Message: "Successfully created user.",
+	}
+}
+
+type updateRoleRequest struct {
+	Role role `json:"role"`
+}
+
+// UpdateUserRole changes an existing account's role (e.g. promoting a
+// citizen to responder). It bumps the user's token_version so any session
+// minted with the old role stops working immediately.
+func (s *Server) UpdateUserRole(w http.ResponseWriter, r *http.Request) api.Response {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+
+	var data updateRoleRequest
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&data); err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("update user role: %w", err),
+			Code:    http.StatusBadRequest,
+			Message: "Invalid update role request.",
+		}
+	}
+
+	if err := s.repository.UpdateUserRole(ctx, userID, data.Role); err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("update user role: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update user role.",
+		}
+	}
+
+	if err := s.repository.IncrementTokenVersion(ctx, userID); err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("update user role: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update user role.",
+		}
+	}
+
+	return api.Response{
+		Code:    http.StatusOK,
+		Message: "Successfully updated user role.",
+	}
+}
+
+// DeleteUser deactivates an account and invalidates any outstanding
+// sessions for it.
+func (s *Server) DeleteUser(w http.ResponseWriter, r *http.Request) api.Response {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+
+	if err := s.repository.DeleteUser(ctx, userID); err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("delete user: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete user.",
+		}
+	}
+
+	// Bumping token_version, same as UpdateUserRole, invalidates every
+	// access and refresh token already issued to this account so the
+	// deactivation takes effect immediately instead of at the access
+	// token's natural 15-minute expiry.
+	if err := s.repository.IncrementTokenVersion(ctx, userID); err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("delete user: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete user.",
+		}
+	}
+
+	return api.Response{
+		Code:    http.StatusOK,
+		Message: "Successfully deleted user.",
+	}
+}
@@ -1,33 +1,59 @@
 package user
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/InternalPointerVariable/ResQLink-Backend/internal/api"
+	"github.com/InternalPointerVariable/ResQLink-Backend/internal/auth"
+	"github.com/InternalPointerVariable/ResQLink-Backend/internal/user/password"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/nbutton23/zxcvbn-go"
 )
 
 type Server struct {
-	repository Repository
+	repository  Repository
+	tokens      *auth.Issuer
+	revocations auth.RevocationStore
+	passwords   *password.Hasher
+	lockouts    auth.LockoutStore
 }
 
-func NewServer(repository Repository) *Server {
+func NewServer(
+	repository Repository,
+	tokens *auth.Issuer,
+	revocations auth.RevocationStore,
+	passwords *password.Hasher,
+	lockouts auth.LockoutStore,
+) *Server {
 	return &Server{
-		repository: repository,
+		repository:  repository,
+		tokens:      tokens,
+		revocations: revocations,
+		passwords:   passwords,
+		lockouts:    lockouts,
 	}
 }
 
+// TokenVersion satisfies auth.TokenVersions so *Server can be handed
+// straight to auth.NewMiddleware.
+func (s *Server) TokenVersion(ctx context.Context, userID string) (int, error) {
+	return s.repository.TokenVersion(ctx, userID)
+}
+
 type role string
 
 const (
 	citizen   role = "citizen"
 	responder role = "responder"
+	admin     role = "admin"
 )
 
 type signUpRequest struct {
@@ -103,8 +129,68 @@ type signInRequest struct {
 }
 
 type signInResponse struct {
-	User  userResponse `json:"user"`
-	Token string       `json:"token"`
+	User         userResponse `json:"user"`
+	AccessToken  string       `json:"accessToken"`
+	RefreshToken string       `json:"refreshToken"`
+}
+
+// toAuthRole maps the user package's own role type to the decoupled
+// auth.Role used in token claims.
+func toAuthRole(r role) auth.Role {
+	switch r {
+	case responder:
+		return auth.RoleResponder
+	case admin:
+		return auth.RoleAdmin
+	default:
+		return auth.RoleCitizen
+	}
+}
+
+const sessionCookieName = "session"
+
+// setSessionCookie lets browser clients authenticate via cookie instead of
+// an Authorization header; AuthMiddleware accepts either.
+func setSessionCookie(w http.ResponseWriter, accessToken string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    accessToken,
+		Path:     "/",
+		MaxAge:   int(auth.DefaultAccessTokenTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s *Server) issueTokens(user userResponse, tokenVersion int) (signInResponse, error) {
+	accessToken, err := s.tokens.NewAccessToken(user.UserID, toAuthRole(user.Role), tokenVersion)
+	if err != nil {
+		return signInResponse{}, fmt.Errorf("issue access token: %w", err)
+	}
+
+	refreshToken, _, err := s.tokens.NewRefreshToken(user.UserID, tokenVersion)
+	if err != nil {
+		return signInResponse{}, fmt.Errorf("issue refresh token: %w", err)
+	}
+
+	return signInResponse{
+		User:         user,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
 }
 
 func (s *Server) SignIn(w http.ResponseWriter, r *http.Request) api.Response {
@@ -121,8 +207,37 @@ func (s *Server) SignIn(w http.ResponseWriter, r *http.Request) api.Response {
 		}
 	}
 
-	response, err := s.repository.SignIn(ctx, data)
+	// The per-email lockout is separate from the per-IP rate limiting
+	// mounted in front of this handler: it catches an attacker who spreads
+	// attempts against one victim across many IPs.
+	if locked, retryAfter, err := s.lockouts.Locked(ctx, data.Email); err == nil && locked {
+		slog.Warn("auth.login.locked", "email", data.Email, "retryAfterSeconds", retryAfter.Seconds())
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+
+		return api.Response{
+			Error:   fmt.Errorf("sign in: %s is locked out", data.Email),
+			Code:    http.StatusTooManyRequests,
+			Message: "Too many failed attempts. Try again later.",
+		}
+	}
+
+	// SignIn verifies the stored hash against data.Password and, if it was
+	// produced with weaker or legacy (pre-Argon2id) parameters, transparently
+	// rehashes it with s.passwords' current params and persists the new hash
+	// in the same transaction as the credential check.
+	user, tokenVersion, err := s.repository.SignIn(ctx, data, s.passwords)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, errInvalidPassword) {
+			locked, retryAfter, lockErr := s.lockouts.RecordFailure(ctx, data.Email)
+			if lockErr == nil && locked {
+				slog.Warn("auth.login.locked", "email", data.Email, "retryAfterSeconds", retryAfter.Seconds())
+				w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+			} else {
+				slog.Warn("auth.login.failed", "email", data.Email)
+			}
+		}
+
 		if errors.Is(err, pgx.ErrNoRows) {
 			return api.Response{
 				Error:   fmt.Errorf("sign in: %w", err),
@@ -146,6 +261,23 @@ func (s *Server) SignIn(w http.ResponseWriter, r *http.Request) api.Response {
 		}
 	}
 
+	if err := s.lockouts.Reset(ctx, data.Email); err != nil {
+		slog.Error("failed to reset login lockout", "email", data.Email, "error", err)
+	}
+
+	response, err := s.issueTokens(user, tokenVersion)
+	if err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("sign in: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to sign in.",
+		}
+	}
+
+	setSessionCookie(w, response.AccessToken)
+
+	slog.Info("auth.login.success", "userId", user.UserID)
+
 	return api.Response{
 		Code:    http.StatusOK,
 		Message: "Successfully signed in.",
@@ -167,7 +299,16 @@ func (s *Server) SignInAnonymous(w http.ResponseWriter, r *http.Request) api.Res
 		}
 	}
 
-	response, err := s.repository.SignInAnonymous(ctx, data.AnonymousID)
+	user, tokenVersion, err := s.repository.SignInAnonymous(ctx, data.AnonymousID)
+	if err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("sign in anon: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to sign in as anonymous.",
+		}
+	}
+
+	response, err := s.issueTokens(user, tokenVersion)
 	if err != nil {
 		return api.Response{
 			Error:   fmt.Errorf("sign in anon: %w", err),
@@ -176,6 +317,8 @@ func (s *Server) SignInAnonymous(w http.ResponseWriter, r *http.Request) api.Res
 		}
 	}
 
+	setSessionCookie(w, response.AccessToken)
+
 	return api.Response{
 		Code:    http.StatusOK,
 		Message: "Successfully signed in as anonymous.",
@@ -183,78 +326,330 @@ func (s *Server) SignInAnonymous(w http.ResponseWriter, r *http.Request) api.Res
 	}
 }
 
-type signOutRequest struct {
-	UserID string `json:"id"`
-	Token  string `json:"token"`
+// SignOut bumps the authenticated caller's token_version, which invalidates
+// every outstanding access and refresh token for that user without
+// requiring a per-token revocation write. The user ID comes from
+// AuthMiddleware's claims, not a client-supplied body, so one account can't
+// force-logout another.
+func (s *Server) SignOut(w http.ResponseWriter, r *http.Request) api.Response {
+	ctx := r.Context()
+
+	userID, ok := WithUser(ctx)
+	if !ok {
+		return api.Response{
+			Error:   fmt.Errorf("sign out: missing authenticated user"),
+			Code:    http.StatusUnauthorized,
+			Message: "Failed to sign out.",
+		}
+	}
+
+	if err := s.repository.IncrementTokenVersion(ctx, userID); err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("sign out: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to sign out.",
+		}
+	}
+
+	clearSessionCookie(w)
+
+	return api.Response{
+		Code:    http.StatusOK,
+		Message: "Successfully signed out.",
+	}
 }
 
-func (s *Server) SignOut(w http.ResponseWriter, r *http.Request) api.Response {
+type changePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// minPasswordStrength is the minimum acceptable zxcvbn score (0-4) for a
+// new password; 3 rejects most dictionary-based and pattern-based guesses
+// while still allowing ordinary passphrases.
+const minPasswordStrength = 3
+
+// ChangePassword requires the caller's current password, enforces a
+// minimum zxcvbn strength score on the new one, and bumps token_version so
+// every outstanding session, the caller's own included, is signed out.
+func (s *Server) ChangePassword(w http.ResponseWriter, r *http.Request) api.Response {
 	ctx := r.Context()
 
-	var data signOutRequest
+	userID, ok := WithUser(ctx)
+	if !ok {
+		return api.Response{
+			Error:   fmt.Errorf("change password: missing authenticated user"),
+			Code:    http.StatusUnauthorized,
+			Message: "Failed to change password.",
+		}
+	}
+
+	var data changePasswordRequest
 
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&data); err != nil {
 		return api.Response{
-			Error:   fmt.Errorf("sign out: %w", err),
+			Error:   fmt.Errorf("change password: %w", err),
 			Code:    http.StatusBadRequest,
-			Message: "Invalid sign out request.",
+			Message: "Invalid change password request.",
 		}
 	}
 
-	if err := s.repository.invalidateSession(ctx, data.Token, data.UserID); err != nil {
+	if score := zxcvbn.PasswordStrength(data.NewPassword, nil).Score; score < minPasswordStrength {
 		return api.Response{
-			Error:   fmt.Errorf("sign out: %w", err),
+			Error:   fmt.Errorf("change password: new password scored %d, want >= %d", score, minPasswordStrength),
+			Code:    http.StatusBadRequest,
+			Message: "New password is too weak.",
+		}
+	}
+
+	if err := s.repository.ChangePassword(ctx, userID, data.CurrentPassword, data.NewPassword, s.passwords); err != nil {
+		if errors.Is(err, errInvalidPassword) {
+			return api.Response{
+				Error:   fmt.Errorf("change password: %w", err),
+				Code:    http.StatusUnauthorized,
+				Message: "Current password is incorrect.",
+			}
+		}
+
+		return api.Response{
+			Error:   fmt.Errorf("change password: %w", err),
 			Code:    http.StatusInternalServerError,
-			Message: "Failed to sign out.",
+			Message: "Failed to change password.",
 		}
 	}
 
+	// Bumping token_version, same as SignOut, invalidates every access and
+	// refresh token issued before this change.
+	if err := s.repository.IncrementTokenVersion(ctx, userID); err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("change password: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to change password.",
+		}
+	}
+
+	clearSessionCookie(w)
+
 	return api.Response{
 		Code:    http.StatusOK,
-		Message: "Successfully signed out.",
+		Message: "Successfully changed password.",
+	}
+}
+
+// GetCSRFToken issues a fresh double-submit csrf_token cookie and returns
+// its value so an SPA can bootstrap the X-CSRF-Token header it must send on
+// subsequent non-GET requests.
+func (s *Server) GetCSRFToken(w http.ResponseWriter, r *http.Request) api.Response {
+	token, err := auth.NewCSRFToken()
+	if err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("get csrf token: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get CSRF token.",
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(auth.DefaultAccessTokenTTL.Seconds()),
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return api.Response{
+		Code:    http.StatusOK,
+		Message: "Successfully issued CSRF token.",
+		Data:    map[string]string{"csrfToken": token},
 	}
 }
 
-func (s *Server) GetSession(w http.ResponseWriter, r *http.Request) api.Response {
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Refresh exchanges a refresh token for a new one: it validates the
+// presented token, confirms it's still at the user's current token version,
+// then issues a fresh access/refresh pair. The presented refresh token is
+// not revoked; it stays usable until it expires or the user's token_version
+// changes.
+func (s *Server) Refresh(w http.ResponseWriter, r *http.Request) api.Response {
 	ctx := r.Context()
 
-	token := r.URL.Query().Get("token")
+	var data refreshRequest
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&data); err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("refresh: %w", err),
+			Code:    http.StatusBadRequest,
+			Message: "Invalid refresh request.",
+		}
+	}
+
+	claims, err := s.tokens.ParseRefreshToken(data.RefreshToken)
+	if err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("refresh: %w", err),
+			Code:    http.StatusUnauthorized,
+			Message: "Invalid or expired refresh token.",
+		}
+	}
+
+	currentVersion, err := s.repository.TokenVersion(ctx, claims.Subject)
+	if err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("refresh: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to refresh session.",
+		}
+	}
+
+	if currentVersion != claims.TokenVersion {
+		return api.Response{
+			Error:   fmt.Errorf("refresh: stale token version"),
+			Code:    http.StatusUnauthorized,
+			Message: "Session has been invalidated, please sign in again.",
+		}
+	}
+
+	user, err := s.repository.GetUser(ctx, claims.Subject)
+	if err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("refresh: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to refresh session.",
+		}
+	}
 
-	result, err := s.repository.validateSessionToken(ctx, token)
+	response, err := s.issueTokens(user, currentVersion)
 	if err != nil {
 		return api.Response{
-			Error:   fmt.Errorf("get session: %w", err),
+			Error:   fmt.Errorf("refresh: %w", err),
 			Code:    http.StatusInternalServerError,
-			Message: "Failed to get user session.",
+			Message: "Failed to refresh session.",
 		}
 	}
 
 	return api.Response{
 		Code:    http.StatusOK,
-		Message: "Successfully fetched user session.",
-		Data:    result,
+		Message: "Successfully refreshed session.",
+		Data:    response,
+	}
+}
+
+type revokeRequest struct {
+	AccessToken string `json:"accessToken"`
+}
+
+// Revoke adds the presented access token's `jti` to the revocation list,
+// so it stops working immediately rather than at its natural expiry.
+func (s *Server) Revoke(w http.ResponseWriter, r *http.Request) api.Response {
+	ctx := r.Context()
+
+	var data revokeRequest
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&data); err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("revoke: %w", err),
+			Code:    http.StatusBadRequest,
+			Message: "Invalid revoke request.",
+		}
+	}
+
+	claims, err := s.tokens.ParseAccessToken(data.AccessToken)
+	if err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("revoke: %w", err),
+			Code:    http.StatusBadRequest,
+			Message: "Invalid access token.",
+		}
+	}
+
+	if err := s.revocations.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return api.Response{
+			Error:   fmt.Errorf("revoke: %w", err),
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to revoke token.",
+		}
+	}
+
+	return api.Response{
+		Code:    http.StatusOK,
+		Message: "Successfully revoked token.",
 	}
 }
 
+// AuthMiddleware authenticates requests using the access token from the
+// `Authorization: Bearer` header or, failing that, the session cookie,
+// validating its signature, expiry, revocation status, and
+// token version before attaching its claims to the request context.
 func (s *Server) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		token, err := r.Cookie("session")
+		rawToken, err := sessionToken(r)
 		if err != nil {
 			slog.Error(err.Error())
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		_, err = s.repository.validateSessionToken(ctx, token.Value)
+		claims, err := s.middleware().Authenticate(ctx, rawToken)
 		if err != nil {
 			slog.Error(err.Error())
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx = auth.WithClaims(ctx, claims)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// middleware builds the decoupled auth.Middleware on demand so *Server
+// doesn't need to satisfy auth.TokenVersions through any field other than
+// itself.
+func (s *Server) middleware() *auth.Middleware {
+	return auth.NewMiddleware(s.tokens, s.revocations, s)
+}
+
+// RequireRole builds a middleware that 403s unless the caller authenticated
+// via AuthMiddleware holds one of roles. It must run after AuthMiddleware.
+func (s *Server) RequireRole(roles ...role) func(http.Handler) http.Handler {
+	allowed := make([]auth.Role, len(roles))
+	for i, r := range roles {
+		allowed[i] = toAuthRole(r)
+	}
+
+	return auth.RequireRole(allowed...)
+}
+
+// WithUser retrieves the authenticated caller's user ID from the request
+// context populated by AuthMiddleware. Handlers should prefer this over
+// trusting a client-supplied ID in the request body or path.
+func WithUser(ctx context.Context) (string, bool) {
+	return auth.UserID(ctx)
+}
+
+// sessionToken reads the access token from the Authorization header
+// (mobile/API clients) or, failing that, the session cookie (browsers).
+func sessionToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+
+	const prefix = "Bearer "
+	if len(header) > len(prefix) && header[:len(prefix)] == prefix {
+		return header[len(prefix):], nil
+	}
+
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return "", errors.New("missing Authorization header or session cookie")
+	}
+
+	return cookie.Value, nil
+}